@@ -0,0 +1,201 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultFormat is the default encoding used for log output.
+	DefaultFormat = "json"
+	// DefaultLevel is the default level for the root logger.
+	DefaultLevel = "info"
+	// DefaultAccessLogFormat is the default encoding used for the HTTP
+	// access log.
+	DefaultAccessLogFormat = "common"
+	// DefaultBackend is the default logging backend.
+	DefaultBackend = "zap"
+)
+
+// Config is the configuration for the diagnostic/logging service. It
+// describes how log records are encoded, where they are written, whether
+// the destination file should be rotated, and what level individual
+// services should log at.
+type Config struct {
+	// Backend selects the logging implementation: "zap" (the default)
+	// or "hclog", for embedding Kapacitor alongside HashiCorp-ecosystem
+	// processes that expect unified hclog-style structured logs.
+	Backend string `toml:"backend"`
+
+	// Format is one of "json", "logfmt" or "console".
+	Format string `toml:"format"`
+	// Level is the level for the root logger, e.g. "debug", "info",
+	// "warn" or "error". It can be changed at runtime via the HTTPD
+	// /kapacitor/v1/logging/level endpoint.
+	Level string `toml:"level"`
+
+	// File is the destination for log output. It may be "STDOUT",
+	// "STDERR", or a path to a file on disk. When it is a file path and
+	// rotation is configured, the file is rotated via lumberjack.
+	File string `toml:"file"`
+
+	// RotationMaxSize is the maximum size in megabytes of the log file
+	// before it gets rotated. A value of 0 disables rotation.
+	RotationMaxSize int `toml:"rotation-max-size"`
+	// RotationMaxAge is the maximum number of days to retain old log
+	// files based on the timestamp encoded in their filename.
+	RotationMaxAge int `toml:"rotation-max-age"`
+	// RotationMaxBackups is the maximum number of old log files to
+	// retain. The default is to retain all old log files.
+	RotationMaxBackups int `toml:"rotation-max-backups"`
+
+	// Levels holds per-service level overrides, e.g. {"slack": "debug",
+	// "httpd": "warn"}. Any service not present here logs at Level.
+	Levels map[string]string `toml:"levels"`
+
+	// AccessLogFile is the destination for the HTTPD access log. It
+	// accepts the same values as File and defaults to File when empty,
+	// i.e. the access log shares the main sink unless configured
+	// otherwise.
+	AccessLogFile string `toml:"access-log-file"`
+	// AccessLogFormat is one of "common" (Apache CLF), "combined" or
+	// "json".
+	AccessLogFormat string `toml:"access-log-format"`
+
+	// AccessLogRotationMaxSize, AccessLogRotationMaxAge and
+	// AccessLogRotationMaxBackups configure rotation of AccessLogFile in
+	// the same way as the corresponding Rotation* fields do for File.
+	AccessLogRotationMaxSize    int `toml:"access-log-rotation-max-size"`
+	AccessLogRotationMaxAge     int `toml:"access-log-rotation-max-age"`
+	AccessLogRotationMaxBackups int `toml:"access-log-rotation-max-backups"`
+
+	// Sampling holds per-service log sampling configuration, keyed by
+	// service name, e.g.:
+	//
+	//	[sampling.kapacitor]
+	//	  initial = 100
+	//	  thereafter = 1000
+	//
+	// protects against services like kapacitor (AlertTriggered,
+	// LoopbackWriteFailed, UDFLog, ...) logging thousands of times a
+	// second under pathological conditions. A service with no entry here
+	// is not sampled.
+	Sampling map[string]SamplingConfig `toml:"sampling"`
+
+	// RateLimits holds hard per-(service, message) caps, for callers
+	// that want excess log calls dropped outright rather than sampled.
+	// It is keyed by "service.message", e.g. "kapacitor.alert triggered".
+	RateLimits map[string]RateLimitConfig `toml:"rate-limits"`
+
+	// OTelLogs, when true, additionally emits every log record as an
+	// OpenTelemetry log event through the globally configured
+	// LoggerProvider, so a record can be correlated with the trace that
+	// produced it. It has no effect when no LoggerProvider is
+	// configured.
+	OTelLogs bool `toml:"otel-logs"`
+}
+
+// SamplingConfig configures zapcore's logarithmic-ish sampler for a
+// single service: the first Initial identical messages in each one
+// second tick are logged, then only every Thereafter-th one.
+type SamplingConfig struct {
+	Initial    int `toml:"initial"`
+	Thereafter int `toml:"thereafter"`
+}
+
+// RateLimitConfig configures a token-bucket limiter for a single
+// (service, message) pair.
+type RateLimitConfig struct {
+	// Rate is the sustained number of log calls per second to allow.
+	Rate float64 `toml:"rate"`
+	// Burst is the maximum number of log calls to allow in a single
+	// burst above Rate.
+	Burst int `toml:"burst"`
+}
+
+// NewConfig returns a Config with the default values set.
+func NewConfig() Config {
+	return Config{
+		Backend:         DefaultBackend,
+		Format:          DefaultFormat,
+		Level:           DefaultLevel,
+		File:            "STDERR",
+		Levels:          make(map[string]string),
+		AccessLogFormat: DefaultAccessLogFormat,
+	}
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "", "zap", "hclog":
+	default:
+		return fmt.Errorf("unknown logging backend %q", c.Backend)
+	}
+
+	switch c.Format {
+	case "json", "logfmt", "console":
+	default:
+		return fmt.Errorf("unknown logging format %q", c.Format)
+	}
+
+	if _, err := parseLevel(c.Level); err != nil {
+		return fmt.Errorf("invalid logging level: %s", err)
+	}
+
+	for service, level := range c.Levels {
+		if _, err := parseLevel(level); err != nil {
+			return fmt.Errorf("invalid logging level for service %q: %s", service, err)
+		}
+	}
+
+	if c.RotationMaxSize < 0 {
+		return fmt.Errorf("rotation-max-size cannot be negative")
+	}
+	if c.RotationMaxAge < 0 {
+		return fmt.Errorf("rotation-max-age cannot be negative")
+	}
+	if c.RotationMaxBackups < 0 {
+		return fmt.Errorf("rotation-max-backups cannot be negative")
+	}
+
+	switch c.AccessLogFormat {
+	case "", "common", "combined", "json":
+	default:
+		return fmt.Errorf("unknown access log format %q", c.AccessLogFormat)
+	}
+	if c.AccessLogRotationMaxSize < 0 {
+		return fmt.Errorf("access-log-rotation-max-size cannot be negative")
+	}
+	if c.AccessLogRotationMaxAge < 0 {
+		return fmt.Errorf("access-log-rotation-max-age cannot be negative")
+	}
+	if c.AccessLogRotationMaxBackups < 0 {
+		return fmt.Errorf("access-log-rotation-max-backups cannot be negative")
+	}
+
+	for service, sc := range c.Sampling {
+		if sc.Initial < 0 || sc.Thereafter < 0 {
+			return fmt.Errorf("invalid sampling config for service %q: initial and thereafter cannot be negative", service)
+		}
+	}
+	for key, rl := range c.RateLimits {
+		if rl.Rate < 0 || rl.Burst < 0 {
+			return fmt.Errorf("invalid rate limit config for %q: rate and burst cannot be negative", key)
+		}
+	}
+
+	return nil
+}
+
+// parseLevel parses a level string as accepted by the config and the
+// logging level HTTP endpoint, e.g. "debug", "Info", "WARN".
+func parseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return l, fmt.Errorf("unknown logging level %q", level)
+	}
+	return l, nil
+}
@@ -0,0 +1,25 @@
+package diagnostic
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsPool holds reusable []zapcore.Field slices for building the
+// variadic keyvalue.T context passed to the various Error methods. It
+// avoids an allocation per call on the common 0/1/2-arg paths without
+// special-casing them by length.
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		return make([]zapcore.Field, 0, 4)
+	},
+}
+
+func getFields() []zapcore.Field {
+	return fieldsPool.Get().([]zapcore.Field)[:0]
+}
+
+func putFields(fields []zapcore.Field) {
+	fieldsPool.Put(fields)
+}
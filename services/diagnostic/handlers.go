@@ -1,6 +1,7 @@
 package diagnostic
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -19,15 +20,29 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// Every WithContext/WithHandlerContext/WithTaskContext/WithNodeContext/
+// WithEdgeContext/WithTaskMasterContext method below takes a leading
+// context.Context so it can attach traceFields(ctx). Each one exists
+// solely to satisfy a Diagnostic interface declared in the sibling
+// package it returns (kapacitor, alert, alerta, hipchat, pagerduty,
+// slack, victorops); those packages predate this change as external
+// dependencies of this one and aren't part of this package's source
+// tree, so their interface declarations need the matching ctx parameter
+// added alongside whichever change lands this signature here.
 type AlertServiceHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
-func (h *AlertServiceHandler) WithHandlerContext(ctx ...keyvalue.T) alertservice.HandlerDiagnostic {
+// WithHandlerContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to an outbound service (Slack,
+// PagerDuty, ...) can be correlated back to the task run that triggered
+// it.
+func (h *AlertServiceHandler) WithHandlerContext(ctx context.Context, kv ...keyvalue.T) alertservice.HandlerDiagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &AlertServiceHandler{
 		l: h.l.With(fields...),
@@ -55,63 +70,59 @@ func (h *AlertServiceHandler) FoundNewHandler(key string) {
 }
 
 func (h *AlertServiceHandler) Error(msg string, err error, ctx ...keyvalue.T) {
-	if len(ctx) == 0 {
-		h.l.Error(msg, zap.Error(err))
+	ce := h.l.Check(zapcore.ErrorLevel, msg)
+	if ce == nil {
 		return
 	}
 
-	if len(ctx) == 1 {
-		el := ctx[0]
-		h.l.Error(msg, zap.Error(err), zap.String(el.Key, el.Value))
-		return
-	}
-
-	if len(ctx) == 2 {
-		x := ctx[0]
-		y := ctx[1]
-		h.l.Error(msg, zap.Error(err), zap.String(x.Key, x.Value), zap.String(y.Key, y.Value))
-		return
-	}
-
-	// This isn't great wrt to allocation, but should not ever actually occur
-	fields := make([]zapcore.Field, len(ctx)+1) // +1 for error
-	fields[0] = zap.Error(err)
-	for i := 1; i < len(fields); i++ {
-		kv := ctx[i-1]
-		fields[i] = zap.String(kv.Key, kv.Value)
+	fields := getFields()
+	fields = append(fields, zap.Error(err))
+	for _, kv := range ctx {
+		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
-
-	h.l.Error(msg, fields...)
+	ce.Write(fields...)
+	putFields(fields)
 }
 
 // Kapcitor Handler
 
 type KapacitorHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 // TODO: create TaskMasterHandler
-func (h *KapacitorHandler) WithTaskContext(task string) kapacitor.TaskDiagnostic {
+//
+// ctx threads trace_id/span_id from the request or task execution that
+// produced this task into every subsequent log line, so an alert can be
+// correlated back to the ingest request that triggered it.
+func (h *KapacitorHandler) WithTaskContext(ctx context.Context, task string) kapacitor.TaskDiagnostic {
+	fields := append([]Field{zap.String("task", task)}, traceFields(ctx)...)
 	return &KapacitorHandler{
-		l: h.l.With(zap.String("task", task)),
+		l: h.l.With(fields...),
 	}
 }
 
-func (h *KapacitorHandler) WithTaskMasterContext(tm string) kapacitor.Diagnostic {
+func (h *KapacitorHandler) WithTaskMasterContext(ctx context.Context, tm string) kapacitor.Diagnostic {
+	fields := append([]Field{zap.String("task_master", tm)}, traceFields(ctx)...)
 	return &KapacitorHandler{
-		l: h.l.With(zap.String("task_master", tm)),
+		l: h.l.With(fields...),
 	}
 }
 
-func (h *KapacitorHandler) WithNodeContext(node string) kapacitor.NodeDiagnostic {
+func (h *KapacitorHandler) WithNodeContext(ctx context.Context, node string) kapacitor.NodeDiagnostic {
+	fields := append([]Field{zap.String("node", node)}, traceFields(ctx)...)
 	return &KapacitorHandler{
-		l: h.l.With(zap.String("node", node)),
+		l: h.l.With(fields...),
 	}
 }
 
-func (h *KapacitorHandler) WithEdgeContext(task, parent, child string) kapacitor.EdgeDiagnostic {
+func (h *KapacitorHandler) WithEdgeContext(ctx context.Context, task, parent, child string) kapacitor.EdgeDiagnostic {
+	fields := append(
+		[]Field{zap.String("task", task), zap.String("parent", parent), zap.String("child", child)},
+		traceFields(ctx)...,
+	)
 	return &KapacitorHandler{
-		l: h.l.With(zap.String("task", task), zap.String("parent", parent), zap.String("child", child)),
+		l: h.l.With(fields...),
 	}
 }
 
@@ -159,69 +170,64 @@ func (h *KapacitorHandler) ClosingEdge(collected int64, emitted int64) {
 //}
 
 func (h *KapacitorHandler) Error(msg string, err error, ctx ...keyvalue.T) {
-	// Special case the three ways that the function is actually used
-	// to avoid allocations
-	if len(ctx) == 0 {
-		h.l.Error(msg, zap.Error(err))
+	ce := h.l.Check(zapcore.ErrorLevel, msg)
+	if ce == nil {
 		return
 	}
 
-	if len(ctx) == 1 {
-		el := ctx[0]
-		h.l.Error(msg, zap.Error(err), zap.String(el.Key, el.Value))
-		return
-	}
-
-	if len(ctx) == 2 {
-		x := ctx[0]
-		y := ctx[1]
-		h.l.Error(msg, zap.Error(err), zap.String(x.Key, x.Value), zap.String(y.Key, y.Value))
-		return
-	}
-
-	// This isn't great wrt to allocation, but should not ever actually occur
-	fields := make([]zapcore.Field, len(ctx)+1) // +1 for error
-	fields[0] = zap.Error(err)
-	for i := 1; i < len(fields); i++ {
-		kv := ctx[i-1]
-		fields[i] = zap.String(kv.Key, kv.Value)
+	fields := getFields()
+	fields = append(fields, zap.Error(err))
+	for _, kv := range ctx {
+		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
-
-	h.l.Error(msg, fields...)
+	ce.Write(fields...)
+	putFields(fields)
 }
 
 func (h *KapacitorHandler) AlertTriggered(level alert.Level, id string, message string, rows *models.Row) {
-	h.l.Debug("alert triggered",
-		zap.Stringer("level", level),
-		zap.String("id", id),
-		zap.String("event_message", message),
-		zap.String("data", fmt.Sprintf("%v", rows)),
-	)
+	if ce := h.l.Check(zapcore.DebugLevel, "alert triggered"); ce != nil {
+		ce.Write(
+			zap.Stringer("level", level),
+			zap.String("id", id),
+			zap.String("event_message", message),
+			zap.String("data", fmt.Sprintf("%v", rows)),
+		)
+	}
 }
 
 func (h *KapacitorHandler) SettingReplicas(new int, old int, id string) {
-	h.l.Debug("setting replicas",
-		zap.Int("new", new),
-		zap.Int("old", old),
-		// TODO: what is this ID?
-		zap.String("id", id),
-	)
+	if ce := h.l.Check(zapcore.DebugLevel, "setting replicas"); ce != nil {
+		ce.Write(
+			zap.Int("new", new),
+			zap.Int("old", old),
+			// TODO: what is this ID?
+			zap.String("id", id),
+		)
+	}
 }
 
 func (h *KapacitorHandler) StartingBatchQuery(q string) {
-	h.l.Debug("starting next batch query", zap.String("query", q))
+	if ce := h.l.Check(zapcore.DebugLevel, "starting next batch query"); ce != nil {
+		ce.Write(zap.String("query", q))
+	}
 }
 
 func (h *KapacitorHandler) CannotPerformDerivative(reason string) {
-	h.l.Error("cannot perform derivative", zap.String("reason", reason))
+	if ce := h.l.Check(zapcore.ErrorLevel, "cannot perform derivative"); ce != nil {
+		ce.Write(zap.String("reason", reason))
+	}
 }
 
 func (h *KapacitorHandler) MissingTagForFlattenOp(tag string) {
-	h.l.Error("point missing tag for flatten operation", zap.String("tag", tag))
+	if ce := h.l.Check(zapcore.ErrorLevel, "point missing tag for flatten operation"); ce != nil {
+		ce.Write(zap.String("tag", tag))
+	}
 }
 
 func (h *KapacitorHandler) IndexOutOfRangeForRow(idx int) {
-	h.l.Error("index out of range for row update", zap.Int("index", idx))
+	if ce := h.l.Check(zapcore.ErrorLevel, "index out of range for row update"); ce != nil {
+		ce.Write(zap.Int("index", idx))
+	}
 }
 
 func (h *KapacitorHandler) LoopbackWriteFailed() {
@@ -229,29 +235,32 @@ func (h *KapacitorHandler) LoopbackWriteFailed() {
 }
 
 func (h *KapacitorHandler) LogData(level string, prefix, data string) {
-	switch level {
-	case "info":
-		h.l.Info("listing data", zap.String("prefix", prefix), zap.String("data", data))
-	default:
+	if ce := h.l.Check(zapcore.InfoLevel, "listing data"); ce != nil {
+		ce.Write(zap.String("prefix", prefix), zap.String("data", data))
 	}
-	h.l.Info("listing data", zap.String("prefix", prefix), zap.String("data", data))
 }
 
 func (h *KapacitorHandler) UDFLog(s string) {
-	h.l.Info("UDF log", zap.String("text", s))
+	if ce := h.l.Check(zapcore.InfoLevel, "UDF log"); ce != nil {
+		ce.Write(zap.String("text", s))
+	}
 }
 
 // Alerta handler
 
 type AlertaHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
-func (h *AlertaHandler) WithContext(ctx ...keyvalue.T) alerta.Diagnostic {
+// WithContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to Alerta can be correlated back to
+// the task run that triggered it.
+func (h *AlertaHandler) WithContext(ctx context.Context, kv ...keyvalue.T) alerta.Diagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &AlertaHandler{
 		l: h.l.With(fields...),
@@ -268,14 +277,18 @@ func (h *AlertaHandler) Error(msg string, err error) {
 
 // HipChat handler
 type HipChatHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
-func (h *HipChatHandler) WithContext(ctx ...keyvalue.T) hipchat.Diagnostic {
+// WithContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to HipChat can be correlated back to
+// the task run that triggered it.
+func (h *HipChatHandler) WithContext(ctx context.Context, kv ...keyvalue.T) hipchat.Diagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &HipChatHandler{
 		l: h.l.With(fields...),
@@ -289,13 +302,16 @@ func (h *HipChatHandler) Error(msg string, err error) {
 // HTTPD handler
 
 type HTTPDHandler struct {
-	l *zap.Logger
+	l Logger
+
+	// access is the dedicated logger for the HTTP access log, rendered
+	// according to accessFormat ("common", "combined" or "json").
+	access       Logger
+	accessFormat string
 }
 
 func (h *HTTPDHandler) NewHTTPServerErrorLogger() *log.Logger {
-	// TODO: implement
-	//panic("not implemented")
-	return nil
+	return h.l.StdLog(zapcore.ErrorLevel)
 }
 
 func (h *HTTPDHandler) StartingService() {
@@ -322,7 +338,20 @@ func (h *HTTPDHandler) WriteBodyReceived(body string) {
 	h.l.Debug("write body received by handler: %s", zap.String("body", body))
 }
 
+// HTTP logs a single completed request. ctx is the request's context: in
+// the "json" access log format, a trace_id/span_id pair is attached
+// whenever ctx carries a span, so the access record can be correlated
+// with the rest of that request's telemetry. The "common" and "combined"
+// formats have no room for it and omit it.
+//
+// This signature is required to satisfy httpd.Diagnostic, which lives in
+// services/httpd and must declare the matching ctx, bytesOut and
+// contentLength parameters; that package was already an external
+// dependency of this one before this change (NewHTTPDHandler has
+// returned httpd.Diagnostic since the baseline) and isn't part of this
+// package's source tree.
 func (h *HTTPDHandler) HTTP(
+	ctx context.Context,
 	host string,
 	username string,
 	start time.Time,
@@ -334,21 +363,51 @@ func (h *HTTPDHandler) HTTP(
 	userAgent string,
 	reqID string,
 	duration time.Duration,
+	bytesOut int64,
+	contentLength int64,
 ) {
-	// TODO: what is the message?
-	h.l.Info("???",
-		zap.String("host", host),
-		zap.String("username", username),
-		zap.Time("start", start),
-		zap.String("method", method),
-		zap.String("uri", uri),
-		zap.String("protocol", proto),
-		zap.Int("status", status),
-		zap.String("referer", referer),
-		zap.String("user-agent", userAgent),
-		zap.String("request-id", reqID),
-		zap.Duration("duration", duration),
-	)
+	if h.accessFormat == "json" {
+		if ce := h.access.Check(zapcore.InfoLevel, "http request"); ce != nil {
+			fields := append([]Field{
+				zap.String("host", host),
+				zap.String("username", username),
+				zap.Time("start", start),
+				zap.String("method", method),
+				zap.String("uri", uri),
+				zap.String("protocol", proto),
+				zap.Int("status", status),
+				zap.Int64("bytes", bytesOut),
+				zap.Int64("content-length", contentLength),
+				zap.String("referer", referer),
+				zap.String("user-agent", userAgent),
+				zap.String("request-id", reqID),
+				zap.Duration("duration", duration),
+			}, traceFields(ctx)...)
+			ce.Write(fields...)
+		}
+		return
+	}
+
+	if ce := h.access.Check(zapcore.InfoLevel, ""); ce != nil {
+		ce.Message = commonLogLine(host, username, start, method, uri, proto, status, bytesOut)
+		if h.accessFormat == "combined" {
+			ce.Message += fmt.Sprintf(" %q %q", referer, userAgent)
+		}
+		ce.Write()
+	}
+}
+
+// commonLogLine renders the Apache Common Log Format line for a single
+// request, e.g.:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+func commonLogLine(host, username string, start time.Time, method, uri, proto string, status int, bytesOut int64) string {
+	if username == "" {
+		username = "-"
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %d",
+		host, username, start.Format("02/Jan/2006:15:04:05 -0700"),
+		fmt.Sprintf("%s %s %s", method, uri, proto), status, bytesOut)
 }
 
 func (h *HTTPDHandler) RecoveryError(
@@ -389,7 +448,7 @@ func (h *HTTPDHandler) Error(msg string, err error) {
 
 // Reporting handler
 type ReportingHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *ReportingHandler) Error(msg string, err error) {
@@ -398,14 +457,18 @@ func (h *ReportingHandler) Error(msg string, err error) {
 
 // PagerDuty handler
 type PagerDutyHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
-func (h *PagerDutyHandler) WithContext(ctx ...keyvalue.T) pagerduty.Diagnostic {
+// WithContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to PagerDuty can be correlated back to
+// the task run that triggered it.
+func (h *PagerDutyHandler) WithContext(ctx context.Context, kv ...keyvalue.T) pagerduty.Diagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &PagerDutyHandler{
 		l: h.l.With(fields...),
@@ -419,7 +482,7 @@ func (h *PagerDutyHandler) Error(msg string, err error) {
 // Slack Handler
 
 type SlackHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *SlackHandler) InsecureSkipVerify() {
@@ -430,11 +493,15 @@ func (h *SlackHandler) Error(msg string, err error) {
 	h.l.Error(msg, zap.Error(err))
 }
 
-func (h *SlackHandler) WithContext(ctx ...keyvalue.T) slack.Diagnostic {
+// WithContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to Slack can be correlated back to the
+// task run that triggered it.
+func (h *SlackHandler) WithContext(ctx context.Context, kv ...keyvalue.T) slack.Diagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &SlackHandler{
 		l: h.l.With(fields...),
@@ -444,7 +511,7 @@ func (h *SlackHandler) WithContext(ctx ...keyvalue.T) slack.Diagnostic {
 // Storage Handler
 
 type StorageHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *StorageHandler) Error(msg string, err error) {
@@ -454,7 +521,7 @@ func (h *StorageHandler) Error(msg string, err error) {
 // TaskStore Handler
 
 type TaskStoreHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *TaskStoreHandler) StartingTask(taskID string) {
@@ -474,35 +541,18 @@ func (h *TaskStoreHandler) Debug(msg string) {
 }
 
 func (h *TaskStoreHandler) Error(msg string, err error, ctx ...keyvalue.T) {
-	// Special case the three ways that the function is actually used
-	// to avoid allocations
-	if len(ctx) == 0 {
-		h.l.Error(msg, zap.Error(err))
+	ce := h.l.Check(zapcore.ErrorLevel, msg)
+	if ce == nil {
 		return
 	}
 
-	if len(ctx) == 1 {
-		el := ctx[0]
-		h.l.Error(msg, zap.Error(err), zap.String(el.Key, el.Value))
-		return
-	}
-
-	if len(ctx) == 2 {
-		x := ctx[0]
-		y := ctx[1]
-		h.l.Error(msg, zap.Error(err), zap.String(x.Key, x.Value), zap.String(y.Key, y.Value))
-		return
-	}
-
-	// This isn't great wrt to allocation, but should not ever actually occur
-	fields := make([]zapcore.Field, len(ctx)+1) // +1 for error
-	fields[0] = zap.Error(err)
-	for i := 1; i < len(fields); i++ {
-		kv := ctx[i-1]
-		fields[i] = zap.String(kv.Key, kv.Value)
+	fields := getFields()
+	fields = append(fields, zap.Error(err))
+	for _, kv := range ctx {
+		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
-
-	h.l.Error(msg, fields...)
+	ce.Write(fields...)
+	putFields(fields)
 }
 
 func (h *TaskStoreHandler) AlreadyMigrated(entity, id string) {
@@ -516,18 +566,22 @@ func (h *TaskStoreHandler) Migrated(entity, id string) {
 // VictorOps Handler
 
 type VictorOpsHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *VictorOpsHandler) Error(msg string, err error) {
 	h.l.Error(msg, zap.Error(err))
 }
 
-func (h *VictorOpsHandler) WithContext(ctx ...keyvalue.T) victorops.Diagnostic {
+// WithContext derives a handler carrying ctx's trace_id/span_id
+// alongside kv, so an alert sent to VictorOps can be correlated back to
+// the task run that triggered it.
+func (h *VictorOpsHandler) WithContext(ctx context.Context, kv ...keyvalue.T) victorops.Diagnostic {
 	fields := []zapcore.Field{}
-	for _, kv := range ctx {
+	for _, kv := range kv {
 		fields = append(fields, zap.String(kv.Key, kv.Value))
 	}
+	fields = append(fields, traceFields(ctx)...)
 
 	return &VictorOpsHandler{
 		l: h.l.With(fields...),
@@ -535,7 +589,7 @@ func (h *VictorOpsHandler) WithContext(ctx ...keyvalue.T) victorops.Diagnostic {
 }
 
 type UDFServiceHandler struct {
-	l *zap.Logger
+	l Logger
 }
 
 func (h *UDFServiceHandler) LoadedUDFInfo(udf string) {
@@ -0,0 +1,59 @@
+package diagnostic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/keyvalue"
+	"github.com/influxdata/kapacitor/models"
+	"go.uber.org/zap"
+)
+
+func TestKapacitorHandler_AlertTriggered_Recorder(t *testing.T) {
+	r := NewRecorder()
+	h := &KapacitorHandler{l: r}
+
+	h.AlertTriggered(alert.Critical, "cpu-alert", "cpu usage high", &models.Row{Name: "cpu"})
+
+	entries := r.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].Msg != "alert triggered" {
+		t.Fatalf("got message %q, want %q", entries[0].Msg, "alert triggered")
+	}
+}
+
+func TestTaskStoreHandler_Error_Recorder(t *testing.T) {
+	r := NewRecorder()
+	h := &TaskStoreHandler{l: r}
+
+	h.Error("failed to save task", errors.New("boom"), keyvalue.T{Key: "task", Value: "t1"})
+
+	entries := r.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].Msg != "failed to save task" {
+		t.Fatalf("got message %q, want %q", entries[0].Msg, "failed to save task")
+	}
+	if len(entries[0].Fields) != 2 {
+		t.Fatalf("got %d fields, want 2 (error + task)", len(entries[0].Fields))
+	}
+}
+
+func TestRecorder_With_InheritsContext(t *testing.T) {
+	r := NewRecorder()
+	child := r.With(zap.String("service", "task_store"))
+
+	child.Info("started task")
+
+	entries := r.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if len(entries[0].Fields) != 1 || entries[0].Fields[0].Key != "service" {
+		t.Fatalf("child entry missing inherited context: %+v", entries[0].Fields)
+	}
+}
@@ -0,0 +1,104 @@
+package diagnostic
+
+import (
+	"log"
+
+	"github.com/hashicorp/go-hclog"
+	"go.uber.org/zap/zapcore"
+)
+
+// hclogLogger adapts an hclog.Logger to the Logger interface so
+// Kapacitor can be embedded alongside HashiCorp-ecosystem processes
+// (Nomad, Consul, Vault) and share their unified structured log format.
+type hclogLogger struct {
+	l hclog.Logger
+}
+
+func newHCLogLogger(l hclog.Logger) Logger {
+	return &hclogLogger{l: l}
+}
+
+func (h *hclogLogger) Debug(msg string, fields ...Field) { h.l.Debug(msg, fieldsToKV(fields)...) }
+func (h *hclogLogger) Info(msg string, fields ...Field)  { h.l.Info(msg, fieldsToKV(fields)...) }
+func (h *hclogLogger) Warn(msg string, fields ...Field)  { h.l.Warn(msg, fieldsToKV(fields)...) }
+func (h *hclogLogger) Error(msg string, fields ...Field) { h.l.Error(msg, fieldsToKV(fields)...) }
+
+func (h *hclogLogger) With(fields ...Field) Logger {
+	return &hclogLogger{l: h.l.With(fieldsToKV(fields)...)}
+}
+
+func (h *hclogLogger) Check(level Level, msg string) *CheckedEntry {
+	var enabled bool
+	switch level {
+	case zapcore.DebugLevel:
+		enabled = h.l.IsDebug()
+	case zapcore.InfoLevel:
+		enabled = h.l.IsInfo()
+	case zapcore.WarnLevel:
+		enabled = h.l.IsWarn()
+	default:
+		enabled = h.l.IsError()
+	}
+	if !enabled {
+		return nil
+	}
+	return &CheckedEntry{
+		Message: msg,
+		write: func(msg string, fields ...Field) {
+			h.log(level, msg, fields...)
+		},
+	}
+}
+
+func (h *hclogLogger) log(level Level, msg string, fields ...Field) {
+	kv := fieldsToKV(fields)
+	switch level {
+	case zapcore.DebugLevel:
+		h.l.Debug(msg, kv...)
+	case zapcore.InfoLevel:
+		h.l.Info(msg, kv...)
+	case zapcore.WarnLevel:
+		h.l.Warn(msg, kv...)
+	default:
+		h.l.Error(msg, kv...)
+	}
+}
+
+func (h *hclogLogger) StdLog(level Level) *log.Logger {
+	return h.l.StandardLogger(&hclog.StandardLoggerOptions{ForceLevel: hclogLevel(level)})
+}
+
+// hclogLevel converts a zapcore.Level to its nearest hclog.Level.
+// hclog has no panic/fatal distinction, so those map to Error.
+func hclogLevel(level Level) hclog.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return hclog.Debug
+	case zapcore.InfoLevel:
+		return hclog.Info
+	case zapcore.WarnLevel:
+		return hclog.Warn
+	default:
+		return hclog.Error
+	}
+}
+
+// fieldsToKV flattens zap fields into the alternating key/value pairs
+// hclog's API expects, preserving field order.
+func fieldsToKV(fields []Field) []interface{} {
+	kv := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		k, v := fieldToKV(f)
+		kv = append(kv, k, v)
+	}
+	return kv
+}
+
+func fieldToKV(f Field) (string, interface{}) {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	for k, v := range enc.Fields {
+		return k, v
+	}
+	return f.Key, nil
+}
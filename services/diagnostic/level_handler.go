@@ -0,0 +1,77 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// levelPath is the route LevelHandler expects to be mounted at: GET and
+// PUT both /kapacitor/v1/logging/level and
+// /kapacitor/v1/logging/level/{service}.
+const levelPath = "/kapacitor/v1/logging/level"
+
+// levelResponse is the JSON body returned by a GET and the JSON body
+// expected (Level only) by a PUT.
+type levelResponse struct {
+	Service string `json:"service,omitempty"`
+	Level   string `json:"level"`
+	Dropped int64  `json:"dropped,omitempty"`
+}
+
+// LevelHandler returns the http.Handler described on the Service
+// interface.
+func (s *service) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, levelPath), "/")
+
+		switch r.Method {
+		case http.MethodGet:
+			s.serveLevel(w, name)
+		case http.MethodPut:
+			s.serveSetLevel(w, r, name)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (s *service) serveLevel(w http.ResponseWriter, name string) {
+	level, err := s.Level(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := levelResponse{Service: name, Level: level.String()}
+	if dropped := s.DroppedLogCounts(); dropped != nil {
+		resp.Dropped = dropped[name]
+	}
+	writeLevelResponse(w, resp)
+}
+
+func (s *service) serveSetLevel(w http.ResponseWriter, r *http.Request, name string) {
+	var req levelResponse
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	level, err := parseLevel(req.Level)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid level %q: %s", req.Level, err), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.SetLevel(name, level); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeLevelResponse(w, levelResponse{Service: name, Level: level.String()})
+}
+
+func writeLevelResponse(w http.ResponseWriter, resp levelResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,69 @@
+package diagnostic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler_GetRoot(t *testing.T) {
+	c := NewConfig()
+	c.Level = "warn"
+	svc, err := NewService(c)
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, levelPath, nil)
+	svc.LevelHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), `"level":"warn"`) {
+		t.Fatalf("got body %q, want it to contain level warn", rec.Body.String())
+	}
+}
+
+func TestLevelHandler_PutService(t *testing.T) {
+	c := NewConfig()
+	c.Levels["slack"] = "info"
+	svc, err := NewService(c)
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, levelPath+"/slack", strings.NewReader(`{"level":"debug"}`))
+	svc.LevelHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	level, err := svc.Level("slack")
+	if err != nil {
+		t.Fatalf("Level: %s", err)
+	}
+	if level.String() != "debug" {
+		t.Fatalf("got level %q, want %q", level, "debug")
+	}
+}
+
+func TestLevelHandler_UnknownService(t *testing.T) {
+	c := NewConfig()
+	svc, err := NewService(c)
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, levelPath+"/nonexistent", nil)
+	svc.LevelHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,138 @@
+package diagnostic
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
+)
+
+// samplerTick is the window over which SamplingConfig.Initial and
+// SamplingConfig.Thereafter apply, matching zap's own convention.
+const samplerTick = time.Second
+
+// droppedCounts tracks how many log records sampling and rate limiting
+// have suppressed per service. They are surfaced to operators via
+// Service.DroppedLogCounts and, per service, in LevelHandler's GET
+// response.
+type droppedCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newDroppedCounts() *droppedCounts {
+	return &droppedCounts{counts: make(map[string]int64)}
+}
+
+func (d *droppedCounts) incr(service string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.counts[service]++
+}
+
+// Snapshot returns a copy of the current per-service dropped-log counts.
+func (d *droppedCounts) Snapshot() map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make(map[string]int64, len(d.counts))
+	for k, v := range d.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// samplingCore wraps core with zapcore's sampler, seeded from cfg, and
+// counts every dropped record against dropped.
+func samplingCore(core zapcore.Core, service string, cfg SamplingConfig, dropped *droppedCounts) zapcore.Core {
+	return zapcore.NewSamplerWithOptions(
+		core,
+		samplerTick,
+		cfg.Initial,
+		cfg.Thereafter,
+		zapcore.SamplerHook(func(_ zapcore.Entry, decision zapcore.SamplingDecision) {
+			if decision&zapcore.LogDropped != 0 {
+				dropped.incr(service)
+			}
+		}),
+	)
+}
+
+// rateLimiter enforces a hard cap on log calls keyed by (service, msg),
+// for callers who'd rather drop excess records outright than sample
+// them. A message with no configured limit is always allowed.
+type rateLimiter struct {
+	configs map[string]RateLimitConfig
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+
+	dropped *droppedCounts
+}
+
+func newRateLimiter(configs map[string]RateLimitConfig, dropped *droppedCounts) *rateLimiter {
+	return &rateLimiter{
+		configs:  configs,
+		limiters: make(map[string]*rate.Limiter),
+		dropped:  dropped,
+	}
+}
+
+// hasLimit reports whether service has any configured rate limits, so
+// callers can skip wrapping its core entirely when it doesn't.
+func (r *rateLimiter) hasLimit(service string) bool {
+	prefix := service + "."
+	for key := range r.configs {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allow reports whether a log call for (service, msg) may proceed.
+func (r *rateLimiter) allow(service, msg string) bool {
+	key := service + "." + msg
+	cfg, ok := r.configs[key]
+	if !ok {
+		return true
+	}
+
+	r.mu.Lock()
+	l, ok := r.limiters[key]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(cfg.Rate), cfg.Burst)
+		r.limiters[key] = l
+	}
+	r.mu.Unlock()
+
+	if l.Allow() {
+		return true
+	}
+	r.dropped.incr(service)
+	return false
+}
+
+// rateLimitCore wraps core so that Check rejects entries that exceed the
+// configured rate limit for (service, entry.Message).
+type rateLimitCore struct {
+	zapcore.Core
+	service string
+	limiter *rateLimiter
+}
+
+func (c rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Core.Enabled(ent.Level) {
+		return ce
+	}
+	if !c.limiter.allow(c.service, ent.Message) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return rateLimitCore{Core: c.Core.With(fields), service: c.service, limiter: c.limiter}
+}
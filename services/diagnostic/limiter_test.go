@@ -0,0 +1,100 @@
+package diagnostic
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDroppedCounts_IncrAndSnapshot(t *testing.T) {
+	d := newDroppedCounts()
+	d.incr("kapacitor")
+	d.incr("kapacitor")
+	d.incr("slack")
+
+	snap := d.Snapshot()
+	if snap["kapacitor"] != 2 {
+		t.Fatalf("got %d, want 2", snap["kapacitor"])
+	}
+	if snap["slack"] != 1 {
+		t.Fatalf("got %d, want 1", snap["slack"])
+	}
+
+	// Snapshot must be a copy: mutating it shouldn't affect the source.
+	snap["kapacitor"] = 100
+	if d.Snapshot()["kapacitor"] != 2 {
+		t.Fatalf("Snapshot result is not independent of the source map")
+	}
+}
+
+func TestSamplingCore_DropsAndCountsExcessRecords(t *testing.T) {
+	inner := &recordingCore{LevelEnabler: zapcore.InfoLevel}
+	dropped := newDroppedCounts()
+	cfg := SamplingConfig{Initial: 1, Thereafter: 1000}
+	core := samplingCore(inner, "kapacitor", cfg, dropped)
+	logger := zap.New(core)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("repeated message")
+	}
+
+	if inner.writes != 1 {
+		t.Fatalf("got %d writes, want 1 (sampler should have dropped the rest)", inner.writes)
+	}
+	if dropped.Snapshot()["kapacitor"] != 4 {
+		t.Fatalf("got %d dropped, want 4", dropped.Snapshot()["kapacitor"])
+	}
+}
+
+func TestRateLimiter_HasLimit(t *testing.T) {
+	r := newRateLimiter(map[string]RateLimitConfig{
+		"kapacitor.alert triggered": {Rate: 1, Burst: 1},
+	}, newDroppedCounts())
+
+	if !r.hasLimit("kapacitor") {
+		t.Fatalf("got false, want true for a configured service")
+	}
+	if r.hasLimit("slack") {
+		t.Fatalf("got true, want false for a service with no configured limits")
+	}
+}
+
+func TestRateLimiter_Allow_EnforcesBurstAndCountsDrops(t *testing.T) {
+	dropped := newDroppedCounts()
+	r := newRateLimiter(map[string]RateLimitConfig{
+		"kapacitor.alert triggered": {Rate: 0, Burst: 1},
+	}, dropped)
+
+	if !r.allow("kapacitor", "alert triggered") {
+		t.Fatalf("first call should be allowed within burst")
+	}
+	if r.allow("kapacitor", "alert triggered") {
+		t.Fatalf("second call should be dropped once the burst is exhausted")
+	}
+	if dropped.Snapshot()["kapacitor"] != 1 {
+		t.Fatalf("got %d dropped, want 1", dropped.Snapshot()["kapacitor"])
+	}
+
+	// A message with no configured limit is always allowed and never
+	// counted as dropped.
+	if !r.allow("kapacitor", "unrelated message") {
+		t.Fatalf("an unconfigured message should always be allowed")
+	}
+}
+
+func TestRateLimitCore_Check_RejectsOverLimitEntries(t *testing.T) {
+	inner := &recordingCore{LevelEnabler: zapcore.InfoLevel}
+	limiter := newRateLimiter(map[string]RateLimitConfig{
+		"kapacitor.hot loop": {Rate: 0, Burst: 1},
+	}, newDroppedCounts())
+	core := rateLimitCore{Core: inner, service: "kapacitor", limiter: limiter}
+	logger := zap.New(core)
+
+	logger.Info("hot loop")
+	logger.Info("hot loop")
+
+	if inner.writes != 1 {
+		t.Fatalf("got %d writes, want 1 (the second call should have been rate-limited)", inner.writes)
+	}
+}
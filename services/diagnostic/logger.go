@@ -0,0 +1,61 @@
+package diagnostic
+
+import (
+	"log"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Level and Field reuse zap's vocabulary for log levels and structured
+// key/value pairs. Every backend below understands them, so callers keep
+// constructing fields with zap.String, zap.Int, etc. regardless of which
+// backend is actually configured.
+type Level = zapcore.Level
+type Field = zapcore.Field
+
+// CheckedEntry is returned by Logger.Check. It is nil when the
+// corresponding level is disabled, so callers can skip building fields
+// (and any expensive stringers) entirely on the common disabled path:
+//
+//	if ce := l.Check(zapcore.DebugLevel, "alert triggered"); ce != nil {
+//		ce.Write(zap.String("id", id), ...)
+//	}
+//
+// Message holds the text that Check was called with and is what Write
+// emits by default. Callers on a path where rendering the message is
+// itself expensive (e.g. the access log's printf-style formats) may
+// overwrite it after confirming the level is enabled, so the rendering
+// only happens when the entry will actually be written.
+type CheckedEntry struct {
+	Message string
+
+	write func(msg string, fields ...Field)
+}
+
+// Write emits the entry with the given fields. It is a no-op on a nil
+// CheckedEntry, i.e. when the level was disabled.
+func (ce *CheckedEntry) Write(fields ...Field) {
+	if ce == nil {
+		return
+	}
+	ce.write(ce.Message, fields...)
+}
+
+// Logger is the logging backend used by every XxxHandler in this
+// package. It is implemented by the zap, hclog and recorder backends
+// below; handlers are written against this interface rather than
+// *zap.Logger directly so that the backend can be swapped via Config.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	With(fields ...Field) Logger
+	Check(level Level, msg string) *CheckedEntry
+
+	// StdLog returns a standard library *log.Logger that writes through
+	// this Logger at the given level, for handing to APIs (like
+	// net/http.Server.ErrorLog) that don't know about structured
+	// logging.
+	StdLog(level Level) *log.Logger
+}
@@ -0,0 +1,115 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// traceFields returns the fields to attach to a log record derived from
+// ctx: trace_id and span_id, when ctx carries a valid span. It returns
+// nil when ctx has no span, e.g. in code paths not reached from an HTTP
+// request or a running task.
+func traceFields(ctx context.Context) []Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+// otelCore tees every record written through it to the global
+// OpenTelemetry LoggerProvider, in addition to writing it through the
+// wrapped core as usual. This is how a Kapacitor alert triggered while
+// handling a traced request shows up alongside that request's other
+// telemetry.
+type otelCore struct {
+	zapcore.Core
+	logger log.Logger
+}
+
+func newOtelCore(core zapcore.Core, service string) zapcore.Core {
+	return &otelCore{
+		Core:   core,
+		logger: global.GetLoggerProvider().Logger(service),
+	}
+}
+
+func (c *otelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &otelCore{Core: c.Core.With(fields), logger: c.logger}
+}
+
+// Check must be overridden rather than inherited from the embedded
+// Core: zapcore.CheckedEntry.AddCore binds whatever Core is passed to
+// it, so leaving Check promoted would bind the wrapped core instead of
+// c, and Write would never be called on c at all.
+// The wrapped core's own Check is still consulted first, so any sampling
+// or rate limiting it applies also decides whether c writes the entry.
+func (c *otelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if c.Core.Check(ent, nil) == nil {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *otelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var record log.Record
+	record.SetTimestamp(ent.Time)
+	record.SetSeverity(otelSeverity(ent.Level))
+	record.SetBody(log.StringValue(ent.Message))
+	for _, f := range fields {
+		k, v := fieldToKV(f)
+		record.AddAttributes(log.KeyValue{Key: k, Value: otelValue(v)})
+	}
+	c.logger.Emit(context.Background(), record)
+
+	return c.Core.Write(ent, fields)
+}
+
+func otelSeverity(level zapcore.Level) log.Severity {
+	switch level {
+	case zapcore.DebugLevel:
+		return log.SeverityDebug
+	case zapcore.InfoLevel:
+		return log.SeverityInfo
+	case zapcore.WarnLevel:
+		return log.SeverityWarn
+	case zapcore.ErrorLevel:
+		return log.SeverityError
+	default:
+		return log.SeverityFatal
+	}
+}
+
+// otelValue converts a decoded zap field value into an otel log.Value,
+// falling back to its string representation for types the log API has
+// no dedicated kind for.
+func otelValue(v interface{}) log.Value {
+	switch v := v.(type) {
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case int64:
+		return log.Int64Value(v)
+	case int:
+		return log.IntValue(v)
+	case float64:
+		return log.Float64Value(v)
+	case error:
+		return log.StringValue(v.Error())
+	default:
+		return log.StringValue(fmt.Sprintf("%v", v))
+	}
+}
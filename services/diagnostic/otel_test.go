@@ -0,0 +1,62 @@
+package diagnostic
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingCore is a minimal zapcore.Core, in the same spirit as
+// handlers_bench_test.go's discardCore, that counts how many times it
+// was written to.
+type recordingCore struct {
+	zapcore.LevelEnabler
+	writes int
+}
+
+func (c *recordingCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *recordingCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+func (c *recordingCore) Write(zapcore.Entry, []zapcore.Field) error {
+	c.writes++
+	return nil
+}
+func (c *recordingCore) Sync() error { return nil }
+
+// TestOtelCore_Check_GatesOnLevel pins down otelCore's enabled/disabled
+// gating: a disabled-level call must not reach the wrapped core and an
+// enabled one must. It does not detect Check being left as a promoted
+// method of the embedded Core, since the wrapped core's own Write would
+// still run in that case.
+func TestOtelCore_Check_GatesOnLevel(t *testing.T) {
+	inner := &recordingCore{LevelEnabler: zapcore.InfoLevel}
+	logger := zap.New(newOtelCore(inner, "test-service"))
+
+	logger.Debug("should be dropped")
+	if inner.writes != 0 {
+		t.Fatalf("got %d writes after a disabled-level call, want 0", inner.writes)
+	}
+
+	logger.Info("should be written")
+	if inner.writes != 1 {
+		t.Fatalf("got %d writes after an enabled-level call, want 1", inner.writes)
+	}
+}
+
+// TestOtelCore_With_PreservesLogger checks that deriving a child core via
+// With keeps teeing to the same OTel logger rather than reverting to a
+// plain (non-OTel) core.
+func TestOtelCore_With_PreservesLogger(t *testing.T) {
+	inner := &recordingCore{LevelEnabler: zapcore.InfoLevel}
+	core := newOtelCore(inner, "test-service")
+	child := core.With([]zapcore.Field{zap.String("service", "kapacitor")})
+
+	if _, ok := child.(*otelCore); !ok {
+		t.Fatalf("got %T from With, want *otelCore", child)
+	}
+}
@@ -0,0 +1,100 @@
+package diagnostic
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a single log record captured by a Recorder.
+type Entry struct {
+	Level  Level
+	Msg    string
+	Fields []Field
+}
+
+// recorderState is the data shared between a Recorder and every Logger
+// derived from it via With, so that assertions against the root
+// Recorder see entries logged through any child.
+type recorderState struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Recorder is an in-memory Logger backend that captures every entry
+// instead of writing it anywhere, for deterministic assertions in unit
+// tests without parsing log text.
+type Recorder struct {
+	state *recorderState
+	ctx   []Field
+}
+
+// NewRecorder returns a Recorder with no captured entries.
+func NewRecorder() *Recorder {
+	return &Recorder{state: &recorderState{}}
+}
+
+// Entries returns a snapshot of every entry logged through r or any
+// Logger derived from it.
+func (r *Recorder) Entries() []Entry {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	out := make([]Entry, len(r.state.entries))
+	copy(out, r.state.entries)
+	return out
+}
+
+func (r *Recorder) record(level Level, msg string, fields ...Field) {
+	entry := Entry{
+		Level:  level,
+		Msg:    msg,
+		Fields: append(append([]Field{}, r.ctx...), fields...),
+	}
+
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.entries = append(r.state.entries, entry)
+}
+
+func (r *Recorder) Debug(msg string, fields ...Field) { r.record(zapcore.DebugLevel, msg, fields...) }
+func (r *Recorder) Info(msg string, fields ...Field)  { r.record(zapcore.InfoLevel, msg, fields...) }
+func (r *Recorder) Warn(msg string, fields ...Field)  { r.record(zapcore.WarnLevel, msg, fields...) }
+func (r *Recorder) Error(msg string, fields ...Field) { r.record(zapcore.ErrorLevel, msg, fields...) }
+
+func (r *Recorder) With(fields ...Field) Logger {
+	return &Recorder{
+		state: r.state,
+		ctx:   append(append([]Field{}, r.ctx...), fields...),
+	}
+}
+
+// Check always reports the level as enabled: the recorder is for tests,
+// where skipping field construction isn't the point, seeing every entry
+// is.
+func (r *Recorder) Check(level Level, msg string) *CheckedEntry {
+	return &CheckedEntry{
+		Message: msg,
+		write: func(msg string, fields ...Field) {
+			r.record(level, msg, fields...)
+		},
+	}
+}
+
+func (r *Recorder) StdLog(level Level) *log.Logger {
+	return log.New(&recorderWriter{r: r, level: level}, "", 0)
+}
+
+// recorderWriter adapts a Recorder to io.Writer for StdLog, recording
+// each write as a single entry with the trailing newline trimmed.
+type recorderWriter struct {
+	r     *Recorder
+	level Level
+}
+
+func (w *recorderWriter) Write(p []byte) (int, error) {
+	w.r.record(w.level, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
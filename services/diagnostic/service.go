@@ -1,6 +1,13 @@
 package diagnostic
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
 	"github.com/influxdata/kapacitor"
 	"github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/alerta"
@@ -13,6 +20,8 @@ import (
 	udfservice "github.com/influxdata/kapacitor/services/udf"
 	"github.com/influxdata/kapacitor/services/victorops"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Service interface {
@@ -27,82 +36,386 @@ type Service interface {
 	NewAlertHandler() alert.Diagnostic
 	NewHipChatHandler() hipchat.Diagnostic
 	NewUDFServiceHandler() udfservice.Diagnostic
+
+	// Level returns the current level of the named service's logger, or
+	// the root level if name is empty. It backs LevelHandler's GET
+	// method.
+	Level(name string) (zapcore.Level, error)
+	// SetLevel changes the level of the named service's logger at
+	// runtime, or the root level if name is empty. It backs
+	// LevelHandler's PUT method. It only takes effect on the zap
+	// backend; see Config.Backend.
+	SetLevel(name string, level zapcore.Level) error
+
+	// LevelHandler returns the http.Handler for
+	// GET/PUT /kapacitor/v1/logging/level[/{service}]: GET reports the
+	// current level (and dropped-record count) for the root logger or a
+	// named service, PUT sets it. This package owns only log
+	// configuration, not HTTP routing, so it exposes the handler rather
+	// than registering it; the httpd service (services/httpd, already an
+	// external dependency of this one -- NewHTTPDHandler has returned
+	// httpd.Diagnostic since the baseline commit, and that package has
+	// never been part of this one's source tree) mounts it at that path
+	// alongside Kapacitor's other routes.
+	LevelHandler() http.Handler
+
+	// DroppedLogCounts returns, per service, how many log records
+	// sampling or rate limiting has suppressed since startup.
+	DroppedLogCounts() map[string]int64
 }
 
+// service builds named loggers on top of a single shared encoder and
+// output sink. A named logger with an override in Config.Levels gets its
+// own zap.AtomicLevel; every other named logger shares the root
+// AtomicLevel (see levelFor).
 type service struct {
-	logger *zap.Logger
+	backend string
+	format  string
+
+	encoder zapcore.Encoder
+	sink    zapcore.WriteSyncer
+
+	accessEncoder zapcore.Encoder
+	accessSink    zapcore.WriteSyncer
+	accessFormat  string
+
+	mu     sync.Mutex
+	level  zap.AtomicLevel
+	levels map[string]zap.AtomicLevel
+
+	sampling map[string]SamplingConfig
+	limiter  *rateLimiter
+	dropped  *droppedCounts
+	otelLogs bool
+
+	// recorder backs every named logger when backend == "recorder".
+	recorder *Recorder
+}
+
+// NewRecorderService returns a Service whose handlers all log through
+// the returned Recorder, for deterministic log assertions in tests
+// without parsing encoded log text.
+func NewRecorderService() (*Recorder, Service) {
+	r := NewRecorder()
+	return r, &service{backend: "recorder", recorder: r}
+}
+
+// NewService creates a diagnostic Service from c. It builds the zapcore
+// encoder/sink described by c and seeds any per-service level overrides
+// from c.Levels.
+func NewService(c Config) (Service, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	encoder, err := newEncoder(c.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	sink, err := newSink(c.File, c.RotationMaxSize, c.RotationMaxAge, c.RotationMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	accessFile := c.AccessLogFile
+	if accessFile == "" {
+		accessFile = c.File
+	}
+	accessEncoder, err := newAccessEncoder(c.AccessLogFormat)
+	if err != nil {
+		return nil, err
+	}
+	accessSink, err := newSink(accessFile, c.AccessLogRotationMaxSize, c.AccessLogRotationMaxAge, c.AccessLogRotationMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	rootLevel, err := parseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := c.Backend
+	if backend == "" {
+		backend = DefaultBackend
+	}
+
+	dropped := newDroppedCounts()
+
+	s := &service{
+		backend:       backend,
+		format:        c.Format,
+		encoder:       encoder,
+		sink:          sink,
+		accessEncoder: accessEncoder,
+		accessSink:    accessSink,
+		accessFormat:  c.AccessLogFormat,
+		level:         zap.NewAtomicLevelAt(rootLevel),
+		levels:        make(map[string]zap.AtomicLevel),
+		sampling:      c.Sampling,
+		limiter:       newRateLimiter(c.RateLimits, dropped),
+		dropped:       dropped,
+		otelLogs:      c.OTelLogs,
+	}
+
+	for name, level := range c.Levels {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		s.levels[name] = zap.NewAtomicLevelAt(lvl)
+	}
+
+	return s, nil
+}
+
+// newHTTPAccessLogger builds the dedicated logger used for the HTTPD
+// access log. It writes to the sink built from the AccessLog* settings:
+// AccessLogFile with its own rotation, or the same destination as File
+// when AccessLogFile is empty.
+func (s *service) newHTTPAccessLogger() Logger {
+	if s.backend == "recorder" {
+		return s.recorder.With(zap.String("service", "http-access"))
+	}
+
+	if s.backend == "hclog" {
+		return newHCLogLogger(hclog.New(&hclog.LoggerOptions{
+			Name:       "http-access",
+			Level:      hclog.Info,
+			Output:     s.accessSink,
+			JSONFormat: s.accessFormat == "json",
+		}))
+	}
+
+	core := zapcore.NewCore(s.accessEncoder, s.accessSink, zapcore.InfoLevel)
+	return newZapLogger(zap.New(core))
 }
 
-func NewService() Service {
-	// TODO: change
-	l := zap.NewExample()
-	return &service{
-		logger: l,
+// newEncoder returns the zapcore.Encoder for the given config format.
+func newEncoder(format string) (zapcore.Encoder, error) {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	switch format {
+	case "json":
+		return zapcore.NewJSONEncoder(cfg), nil
+	case "logfmt":
+		return zapcore.NewConsoleEncoder(cfg), nil
+	case "console":
+		cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		return zapcore.NewConsoleEncoder(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown logging format %q", format)
+	}
+}
+
+// newSink returns the zapcore.WriteSyncer to write log output to,
+// wrapping it with lumberjack-based rotation when file names a path on
+// disk and rotation is configured.
+func newSink(file string, maxSize, maxAge, maxBackups int) (zapcore.WriteSyncer, error) {
+	switch file {
+	case "", "STDOUT":
+		return zapcore.Lock(os.Stdout), nil
+	case "STDERR":
+		return zapcore.Lock(os.Stderr), nil
+	default:
+		if maxSize == 0 {
+			f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			return zapcore.AddSync(f), nil
+		}
+		var w io.Writer = &lumberjack.Logger{
+			Filename:   file,
+			MaxSize:    maxSize,
+			MaxAge:     maxAge,
+			MaxBackups: maxBackups,
+		}
+		return zapcore.AddSync(w), nil
+	}
+}
+
+// newAccessEncoder returns the zapcore.Encoder for the HTTP access log.
+// "common" and "combined" render a plain Apache-style line built by the
+// caller as the entry message; "json" renders the usual structured
+// fields.
+func newAccessEncoder(format string) (zapcore.Encoder, error) {
+	switch format {
+	case "", "common", "combined":
+		return zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+			MessageKey:  "msg",
+			LineEnding:  zapcore.DefaultLineEnding,
+			EncodeLevel: zapcore.CapitalLevelEncoder,
+		}), nil
+	case "json":
+		cfg := zap.NewProductionEncoderConfig()
+		cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewJSONEncoder(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+}
+
+// levelFor returns the AtomicLevel for the named service. If name has no
+// explicit override configured, it shares the root AtomicLevel itself
+// rather than a copy, so a later root-level change via SetLevel("", ...)
+// still reaches loggers already built for it.
+func (s *service) levelFor(name string) zap.AtomicLevel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lvl, ok := s.levels[name]; ok {
+		return lvl
+	}
+	s.levels[name] = s.level
+	return s.level
+}
+
+// namedLogger returns a Logger for the given service name, tagged with a
+// "service" field and backed by the AtomicLevel levelFor returns.
+func (s *service) namedLogger(name string) Logger {
+	if s.backend == "recorder" {
+		return s.recorder.With(zap.String("service", name))
+	}
+
+	lvl := s.levelFor(name)
+
+	if s.backend == "hclog" {
+		return newHCLogLogger(hclog.New(&hclog.LoggerOptions{
+			Name:       name,
+			Level:      hclogLevel(lvl.Level()),
+			Output:     s.sink,
+			JSONFormat: s.format == "json",
+		}))
+	}
+
+	core := zapcore.NewCore(s.encoder, s.sink, lvl)
+	if sc, ok := s.sampling[name]; ok {
+		core = samplingCore(core, name, sc, s.dropped)
+	}
+	if s.limiter.hasLimit(name) {
+		core = rateLimitCore{Core: core, service: name, limiter: s.limiter}
+	}
+	if s.otelLogs {
+		core = newOtelCore(core, name)
+	}
+	return newZapLogger(zap.New(core).With(zap.String("service", name)))
+}
+
+// DroppedLogCounts returns, per service, how many log records sampling
+// or rate limiting has suppressed since startup.
+func (s *service) DroppedLogCounts() map[string]int64 {
+	if s.dropped == nil {
+		return nil
+	}
+	return s.dropped.Snapshot()
+}
+
+func (s *service) Level(name string) (zapcore.Level, error) {
+	if s.backend == "recorder" {
+		return zapcore.DebugLevel, nil
+	}
+
+	if name == "" {
+		return s.level.Level(), nil
+	}
+
+	s.mu.Lock()
+	lvl, ok := s.levels[name]
+	s.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("unknown service %q", name)
+	}
+	return lvl.Level(), nil
+}
+
+func (s *service) SetLevel(name string, level zapcore.Level) error {
+	if s.backend == "recorder" {
+		// The recorder backend always records every entry.
+		return nil
+	}
+
+	if name == "" {
+		s.level.SetLevel(level)
+		return nil
+	}
+
+	s.mu.Lock()
+	lvl, ok := s.levels[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown service %q", name)
 	}
+	lvl.SetLevel(level)
+	return nil
 }
 
 func (s *service) NewVictorOpsHandler() victorops.Diagnostic {
 	return &VictorOpsHandler{
-		l: s.logger.With(zap.String("service", "victorops")),
+		l: s.namedLogger("victorops"),
 	}
 }
 
 func (s *service) NewSlackHandler() slack.Diagnostic {
 	return &SlackHandler{
-		l: s.logger.With(zap.String("service", "slack")),
+		l: s.namedLogger("slack"),
 	}
 }
 
 func (s *service) NewTaskStoreHandler() task_store.Diagnostic {
 	return &TaskStoreHandler{
-		l: s.logger.With(zap.String("service", "task_store")),
+		l: s.namedLogger("task_store"),
 	}
 }
 
 func (s *service) NewReportingHandler() reporting.Diagnostic {
 	return &ReportingHandler{
-		l: s.logger.With(zap.String("service", "reporting")),
+		l: s.namedLogger("reporting"),
 	}
 }
 
 func (s *service) NewStorageHandler() storage.Diagnostic {
 	return &StorageHandler{
-		l: s.logger.With(zap.String("service", "storage")),
+		l: s.namedLogger("storage"),
 	}
 }
 
 func (s *service) NewHTTPDHandler() httpd.Diagnostic {
 	return &HTTPDHandler{
-		l: s.logger.With(zap.String("service", "http")),
+		l:            s.namedLogger("http"),
+		access:       s.newHTTPAccessLogger(),
+		accessFormat: s.accessFormat,
 	}
 }
 
 func (s *service) NewAlertaHandler() alerta.Diagnostic {
 	return &AlertaHandler{
-		l: s.logger.With(zap.String("service", "alerta")),
+		l: s.namedLogger("alerta"),
 	}
 }
 
 func (s *service) NewKapacitorHandler() kapacitor.Diagnostic {
 	return &KapacitorHandler{
-		l: s.logger.With(zap.String("service", "kapacitor")), // TODO: what here
+		l: s.namedLogger("kapacitor"),
 	}
 }
 
 func (s *service) NewAlertHandler() alert.Diagnostic {
 	return &AlertHandler{
-		l: s.logger.With(zap.String("service", "alert")),
+		l: s.namedLogger("alert"),
 	}
 }
 
 func (s *service) NewHipChatHandler() hipchat.Diagnostic {
 	return &HipChatHandler{
-		l: s.logger.With(zap.String("service", "hipchat")),
+		l: s.namedLogger("hipchat"),
 	}
 }
 
 func (s *service) NewUDFServiceHandler() udfservice.Diagnostic {
 	return &UDFServiceHandler{
-		l: s.logger.With(zap.String("service", "udf")),
+		l: s.namedLogger("udf"),
 	}
 }
@@ -0,0 +1,36 @@
+package diagnostic
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// TestService_SetLevel_RootPropagatesToUnconfiguredService guards
+// against levelFor forking an independent AtomicLevel for a service with
+// no explicit override: a root-level change must still reach a logger
+// that was already built before the change.
+func TestService_SetLevel_RootPropagatesToUnconfiguredService(t *testing.T) {
+	c := NewConfig()
+	c.Level = "info"
+	svc, err := NewService(c)
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+
+	// Build the kapacitor handler's logger before changing the root
+	// level, as NewKapacitorHandler would at startup.
+	svc.NewKapacitorHandler()
+
+	if err := svc.SetLevel("", zapcore.DebugLevel); err != nil {
+		t.Fatalf("SetLevel: %s", err)
+	}
+
+	level, err := svc.Level("kapacitor")
+	if err != nil {
+		t.Fatalf("Level: %s", err)
+	}
+	if level.String() != "debug" {
+		t.Fatalf("got level %q for an unconfigured service after a root change, want %q", level, "debug")
+	}
+}
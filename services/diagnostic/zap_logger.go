@@ -0,0 +1,50 @@
+package diagnostic
+
+import (
+	"log"
+
+	"go.uber.org/zap"
+)
+
+// zapLogger adapts a *zap.Logger to the Logger interface. It is the
+// default backend.
+type zapLogger struct {
+	l *zap.Logger
+}
+
+func newZapLogger(l *zap.Logger) Logger {
+	return &zapLogger{l: l}
+}
+
+func (z *zapLogger) Debug(msg string, fields ...Field) { z.l.Debug(msg, fields...) }
+func (z *zapLogger) Info(msg string, fields ...Field)  { z.l.Info(msg, fields...) }
+func (z *zapLogger) Warn(msg string, fields ...Field)  { z.l.Warn(msg, fields...) }
+func (z *zapLogger) Error(msg string, fields ...Field) { z.l.Error(msg, fields...) }
+
+func (z *zapLogger) With(fields ...Field) Logger {
+	return &zapLogger{l: z.l.With(fields...)}
+}
+
+func (z *zapLogger) Check(level Level, msg string) *CheckedEntry {
+	ce := z.l.Check(level, msg)
+	if ce == nil {
+		return nil
+	}
+	return &CheckedEntry{
+		Message: msg,
+		write: func(msg string, fields ...Field) {
+			ce.Message = msg
+			ce.Write(fields...)
+		},
+	}
+}
+
+func (z *zapLogger) StdLog(level Level) *log.Logger {
+	stdLog, err := zap.NewStdLogAt(z.l, level)
+	if err != nil {
+		// Only fails for an invalid level, which cannot happen since
+		// Level is always one of the zapcore.Level constants.
+		panic(err)
+	}
+	return stdLog
+}